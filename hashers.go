@@ -0,0 +1,336 @@
+package pwdatav3
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ASPNetV3Hasher adapts the package's native PWHash (PBKDF2-HMAC-SHA256, ASP.NET
+// Core Identity's "v3" format) for use as a Hasher in a Passwd. It has no textual
+// prefix: its encoded form is the same base64 binary blob PWHash.MarshalText always
+// produced, so Passwd recognises it by elimination rather than by a leading "$".
+type ASPNetV3Hasher struct {
+	// Iter is the iteration count used for new hashes; DefaultIter if zero.
+	Iter int
+}
+
+// Hash returns a PWHash-encoded hash of pw using h.Iter iterations.
+func (h *ASPNetV3Hasher) Hash(pw []byte) ([]byte, error) {
+	iter := h.Iter
+	if iter == 0 {
+		iter = DefaultIter
+	}
+	pd, err := New(string(pw), iter)
+	if err != nil {
+		return nil, err
+	}
+	return pd.MarshalText()
+}
+
+// Verify reports whether pw matches the PWHash encoded in encoded.
+func (h *ASPNetV3Hasher) Verify(pw, encoded []byte) (bool, error) {
+	var pd PWHash
+	if err := pd.UnmarshalText(encoded); err != nil {
+		return false, err
+	}
+	return pd.Verify(string(pw)), nil
+}
+
+// Prefix returns "": the ASP.NET v3 form carries no textual marker.
+func (h *ASPNetV3Hasher) Prefix() string { return "" }
+
+// IsPreferred reports whether encoded already uses at least h's iteration count.
+func (h *ASPNetV3Hasher) IsPreferred(encoded []byte) bool {
+	iter := h.Iter
+	if iter == 0 {
+		iter = DefaultIter
+	}
+	var pd PWHash
+	if err := pd.UnmarshalText(encoded); err != nil {
+		return false
+	}
+	return int(pd.iter) >= iter
+}
+
+// BcryptHasher adapts golang.org/x/crypto/bcrypt for use as a Hasher in a Passwd.
+type BcryptHasher struct {
+	// Cost is the bcrypt work factor used for new hashes; bcrypt.DefaultCost if zero.
+	Cost int
+}
+
+// Hash returns a bcrypt hash of pw at h.Cost.
+func (h *BcryptHasher) Hash(pw []byte) ([]byte, error) {
+	cost := h.Cost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return bcrypt.GenerateFromPassword(pw, cost)
+}
+
+// Verify reports whether pw matches the bcrypt hash encoded.
+func (h *BcryptHasher) Verify(pw, encoded []byte) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(encoded, pw)
+	switch err {
+	case nil:
+		return true, nil
+	case bcrypt.ErrMismatchedHashAndPassword:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// Prefix returns "$2", matching the "$2a$", "$2b$" and "$2y$" bcrypt variants.
+func (h *BcryptHasher) Prefix() string { return "$2" }
+
+// IsPreferred reports whether encoded was hashed at a cost at least h.Cost.
+func (h *BcryptHasher) IsPreferred(encoded []byte) bool {
+	cost := h.Cost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	got, err := bcrypt.Cost(encoded)
+	return err == nil && got >= cost
+}
+
+// Argon2idDefaultTime, Argon2idDefaultMemory (in KiB) and Argon2idDefaultThreads are
+// the argon2id parameters used by a zero-valued Argon2idHasher, chosen to match the
+// argon2 package's own recommended interactive defaults.
+const (
+	Argon2idDefaultTime    = 1
+	Argon2idDefaultMemory  = 64 * 1024
+	Argon2idDefaultThreads = 4
+	argon2idSaltLen        = 16
+	argon2idKeyLen         = 32
+)
+
+// Argon2idHasher adapts golang.org/x/crypto/argon2's Argon2id for use as a Hasher
+// in a Passwd, encoding hashes in the conventional PHC string format, e.g.
+// "$argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>" with raw (unpadded) base64 fields.
+type Argon2idHasher struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+func (h *Argon2idHasher) params() (time, memory uint32, threads uint8) {
+	time, memory, threads = h.Time, h.Memory, h.Threads
+	if time == 0 {
+		time = Argon2idDefaultTime
+	}
+	if memory == 0 {
+		memory = Argon2idDefaultMemory
+	}
+	if threads == 0 {
+		threads = Argon2idDefaultThreads
+	}
+	return
+}
+
+// Hash returns an argon2id hash of pw using h's parameters and a fresh random salt.
+func (h *Argon2idHasher) Hash(pw []byte) ([]byte, error) {
+	time, memory, threads := h.params()
+	salt := make([]byte, argon2idSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("cannot make salt value: %v", err)
+	}
+	key := argon2.IDKey(pw, salt, time, memory, threads, argon2idKeyLen)
+	return []byte(fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		memory, time, threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))), nil
+}
+
+// Verify reports whether pw matches the argon2id hash encoded.
+func (h *Argon2idHasher) Verify(pw, encoded []byte) (bool, error) {
+	_, time, memory, threads, salt, key, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	got := argon2.IDKey(pw, salt, time, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(got, key) == 1, nil
+}
+
+// Prefix returns "$argon2id$".
+func (h *Argon2idHasher) Prefix() string { return "$argon2id$" }
+
+// IsPreferred reports whether encoded already uses h's current parameters.
+func (h *Argon2idHasher) IsPreferred(encoded []byte) bool {
+	_, time, memory, threads, _, _, err := parseArgon2id(encoded)
+	if err != nil {
+		return false
+	}
+	wantTime, wantMemory, wantThreads := h.params()
+	return time == wantTime && memory == wantMemory && threads == wantThreads
+}
+
+// parseArgon2id decodes a "$argon2id$v=..$m=..,t=..,p=..$salt$hash" string.
+func parseArgon2id(encoded []byte) (version int, time, memory uint32, threads uint8, salt, key []byte, err error) {
+	fields := strings.Split(string(encoded), "$")
+	if len(fields) != 6 || fields[0] != "" || fields[1] != "argon2id" {
+		return 0, 0, 0, 0, nil, nil, ErrCorrupt
+	}
+	vField, ok := strings.CutPrefix(fields[2], "v=")
+	if !ok {
+		return 0, 0, 0, 0, nil, nil, ErrCorrupt
+	}
+	version, err = strconv.Atoi(vField)
+	if err != nil {
+		return 0, 0, 0, 0, nil, nil, ErrCorrupt
+	}
+	var t, m uint64
+	var p uint64
+	seen := map[string]bool{}
+	for _, kv := range strings.Split(fields[3], ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return 0, 0, 0, 0, nil, nil, ErrCorrupt
+		}
+		n, perr := strconv.ParseUint(parts[1], 10, 32)
+		if perr != nil {
+			return 0, 0, 0, 0, nil, nil, ErrCorrupt
+		}
+		switch parts[0] {
+		case "m":
+			m = n
+		case "t":
+			t = n
+		case "p":
+			p = n
+		default:
+			return 0, 0, 0, 0, nil, nil, ErrCorrupt
+		}
+		seen[parts[0]] = true
+	}
+	if !seen["m"] || !seen["t"] || !seen["p"] {
+		return 0, 0, 0, 0, nil, nil, ErrCorrupt
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(fields[4]); err != nil {
+		return 0, 0, 0, 0, nil, nil, ErrCorrupt
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(fields[5]); err != nil {
+		return 0, 0, 0, 0, nil, nil, ErrCorrupt
+	}
+	return version, uint32(t), uint32(m), uint8(p), salt, key, nil
+}
+
+// ScryptDefaultLogN, ScryptDefaultR and ScryptDefaultP are the scrypt parameters
+// used by a zero-valued ScryptHasher: N=2^ScryptDefaultLogN, matching scrypt's
+// own recommended interactive cost.
+const (
+	ScryptDefaultLogN = 15
+	ScryptDefaultR    = 8
+	ScryptDefaultP    = 1
+	scryptSaltLen     = 16
+	scryptKeyLen      = 32
+)
+
+// ScryptHasher adapts golang.org/x/crypto/scrypt for use as a Hasher in a Passwd,
+// encoding hashes as "$scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash>" with raw
+// (unpadded) base64 fields.
+type ScryptHasher struct {
+	LogN int // N = 1 << LogN
+	R    int
+	P    int
+}
+
+func (h *ScryptHasher) params() (logN, r, p int) {
+	logN, r, p = h.LogN, h.R, h.P
+	if logN == 0 {
+		logN = ScryptDefaultLogN
+	}
+	if r == 0 {
+		r = ScryptDefaultR
+	}
+	if p == 0 {
+		p = ScryptDefaultP
+	}
+	return
+}
+
+// Hash returns a scrypt hash of pw using h's parameters and a fresh random salt.
+func (h *ScryptHasher) Hash(pw []byte) ([]byte, error) {
+	logN, r, p := h.params()
+	salt := make([]byte, scryptSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("cannot make salt value: %v", err)
+	}
+	key, err := scrypt.Key(pw, salt, 1<<uint(logN), r, p, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s",
+		logN, r, p,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))), nil
+}
+
+// Verify reports whether pw matches the scrypt hash encoded.
+func (h *ScryptHasher) Verify(pw, encoded []byte) (bool, error) {
+	logN, r, p, salt, key, err := parseScrypt(encoded)
+	if err != nil {
+		return false, err
+	}
+	got, err := scrypt.Key(pw, salt, 1<<uint(logN), r, p, len(key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(got, key) == 1, nil
+}
+
+// Prefix returns "$scrypt$".
+func (h *ScryptHasher) Prefix() string { return "$scrypt$" }
+
+// IsPreferred reports whether encoded already uses h's current parameters.
+func (h *ScryptHasher) IsPreferred(encoded []byte) bool {
+	logN, r, p, _, _, err := parseScrypt(encoded)
+	if err != nil {
+		return false
+	}
+	wantLogN, wantR, wantP := h.params()
+	return logN == wantLogN && r == wantR && p == wantP
+}
+
+// parseScrypt decodes a "$scrypt$ln=..,r=..,p=..$salt$hash" string.
+func parseScrypt(encoded []byte) (logN, r, p int, salt, key []byte, err error) {
+	fields := strings.Split(string(encoded), "$")
+	if len(fields) != 5 || fields[0] != "" || fields[1] != "scrypt" {
+		return 0, 0, 0, nil, nil, ErrCorrupt
+	}
+	for _, kv := range strings.Split(fields[2], ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return 0, 0, 0, nil, nil, ErrCorrupt
+		}
+		n, serr := strconv.Atoi(parts[1])
+		if serr != nil {
+			return 0, 0, 0, nil, nil, ErrCorrupt
+		}
+		switch parts[0] {
+		case "ln":
+			logN = n
+		case "r":
+			r = n
+		case "p":
+			p = n
+		default:
+			return 0, 0, 0, nil, nil, ErrCorrupt
+		}
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(fields[3]); err != nil {
+		return 0, 0, 0, nil, nil, ErrCorrupt
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(fields[4]); err != nil {
+		return 0, 0, 0, nil, nil, ErrCorrupt
+	}
+	return logN, r, p, salt, key, nil
+}
@@ -0,0 +1,124 @@
+package pwdatav3
+
+import "testing"
+
+func TestASPNetV3Hasher(t *testing.T) {
+	h := &ASPNetV3Hasher{Iter: DefaultIter}
+	encoded, err := h.Hash([]byte("In2Egypt!"))
+	if err != nil {
+		t.Fatalf("Hash: got error %v", err)
+	}
+	ok, err := h.Verify([]byte("In2Egypt!"), encoded)
+	if err != nil || !ok {
+		t.Errorf("Verify correct password: got ok=%v err=%v", ok, err)
+	}
+	ok, err = h.Verify([]byte("wrong"), encoded)
+	if err != nil || ok {
+		t.Errorf("Verify wrong password: got ok=%v err=%v", ok, err)
+	}
+	if h.Prefix() != "" {
+		t.Errorf("Prefix: want empty string; got %q", h.Prefix())
+	}
+	if !h.IsPreferred(encoded) {
+		t.Errorf("IsPreferred: want true for a hash at h's own iteration count")
+	}
+	stronger, err := New("In2Egypt!", DefaultIter*2)
+	if err != nil {
+		t.Fatalf("New: got error %v", err)
+	}
+	strongEncoded, err := stronger.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText: got error %v", err)
+	}
+	if !h.IsPreferred(strongEncoded) {
+		t.Errorf("IsPreferred: want true for a hash above h's configured iteration count")
+	}
+	weaker := &ASPNetV3Hasher{Iter: DefaultIter * 2}
+	if weaker.IsPreferred(encoded) {
+		t.Errorf("IsPreferred: want false for a hash below h's configured iteration count")
+	}
+}
+
+func TestBcryptHasher(t *testing.T) {
+	h := &BcryptHasher{Cost: 4} // lowest allowed cost, to keep the test fast
+	encoded, err := h.Hash([]byte("In2Egypt!"))
+	if err != nil {
+		t.Fatalf("Hash: got error %v", err)
+	}
+	ok, err := h.Verify([]byte("In2Egypt!"), encoded)
+	if err != nil || !ok {
+		t.Errorf("Verify correct password: got ok=%v err=%v", ok, err)
+	}
+	ok, err = h.Verify([]byte("wrong"), encoded)
+	if err != nil || ok {
+		t.Errorf("Verify wrong password: got ok=%v err=%v", ok, err)
+	}
+	if h.Prefix() != "$2" {
+		t.Errorf("Prefix: want %q; got %q", "$2", h.Prefix())
+	}
+	if !h.IsPreferred(encoded) {
+		t.Errorf("IsPreferred: want true for a hash at h's own cost")
+	}
+	weaker := &BcryptHasher{Cost: 5}
+	if weaker.IsPreferred(encoded) {
+		t.Errorf("IsPreferred: want false for a hash below h's configured cost")
+	}
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	h := &Argon2idHasher{Time: 1, Memory: 8 * 1024, Threads: 1} // small, to keep the test fast
+	encoded, err := h.Hash([]byte("In2Egypt!"))
+	if err != nil {
+		t.Fatalf("Hash: got error %v", err)
+	}
+	ok, err := h.Verify([]byte("In2Egypt!"), encoded)
+	if err != nil || !ok {
+		t.Errorf("Verify correct password: got ok=%v err=%v", ok, err)
+	}
+	ok, err = h.Verify([]byte("wrong"), encoded)
+	if err != nil || ok {
+		t.Errorf("Verify wrong password: got ok=%v err=%v", ok, err)
+	}
+	if h.Prefix() != "$argon2id$" {
+		t.Errorf("Prefix: want %q; got %q", "$argon2id$", h.Prefix())
+	}
+	if !h.IsPreferred(encoded) {
+		t.Errorf("IsPreferred: want true for a hash at h's own parameters")
+	}
+	other := &Argon2idHasher{Time: 2, Memory: 8 * 1024, Threads: 1}
+	if other.IsPreferred(encoded) {
+		t.Errorf("IsPreferred: want false for a hash with different parameters")
+	}
+	if _, _, _, _, _, _, err := parseArgon2id([]byte("$argon2id$v=19x$m=1024,t=1,p=1$YWJjZA$YWJjZA")); err != ErrCorrupt {
+		t.Errorf("parseArgon2id with trailing garbage in version field: want %v; got %v", ErrCorrupt, err)
+	}
+	if _, _, _, _, _, _, err := parseArgon2id([]byte("$argon2id$v=19$m=1024x,t=1,p=1$YWJjZA$YWJjZA")); err != ErrCorrupt {
+		t.Errorf("parseArgon2id with trailing garbage in params field: want %v; got %v", ErrCorrupt, err)
+	}
+}
+
+func TestScryptHasher(t *testing.T) {
+	h := &ScryptHasher{LogN: 10, R: 8, P: 1} // small, to keep the test fast
+	encoded, err := h.Hash([]byte("In2Egypt!"))
+	if err != nil {
+		t.Fatalf("Hash: got error %v", err)
+	}
+	ok, err := h.Verify([]byte("In2Egypt!"), encoded)
+	if err != nil || !ok {
+		t.Errorf("Verify correct password: got ok=%v err=%v", ok, err)
+	}
+	ok, err = h.Verify([]byte("wrong"), encoded)
+	if err != nil || ok {
+		t.Errorf("Verify wrong password: got ok=%v err=%v", ok, err)
+	}
+	if h.Prefix() != "$scrypt$" {
+		t.Errorf("Prefix: want %q; got %q", "$scrypt$", h.Prefix())
+	}
+	if !h.IsPreferred(encoded) {
+		t.Errorf("IsPreferred: want true for a hash at h's own parameters")
+	}
+	other := &ScryptHasher{LogN: 11, R: 8, P: 1}
+	if other.IsPreferred(encoded) {
+		t.Errorf("IsPreferred: want false for a hash with different parameters")
+	}
+}
@@ -0,0 +1,100 @@
+package pwdatav3
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrUnknownPrefix is returned by Passwd.Passwd when no configured Hasher recognises
+// the prefix of a stored hash.
+var ErrUnknownPrefix = errors.New("pwdatav3: no hasher recognises this encoded hash")
+
+// Hasher is implemented by a single password hashing algorithm that can be plugged
+// into a Passwd. Each Hasher recognises its own encoded hashes, typically by a
+// distinct leading "$name$" prefix, so several algorithms can be supported side by
+// side and phased in and out over time without invalidating existing stored hashes.
+type Hasher interface {
+	// Hash returns a freshly encoded hash of pw using this algorithm's current
+	// (preferred) parameters.
+	Hash(pw []byte) ([]byte, error)
+
+	// Verify reports whether pw matches encoded, a hash previously produced by Hash.
+	Verify(pw, encoded []byte) (bool, error)
+
+	// Prefix identifies the encoded hashes this Hasher produces and recognises.
+	// A Hasher with no distinguishing prefix, such as the ASP.NET v3 binary form,
+	// may return the empty string; Passwd then routes to it any stored hash that
+	// does not begin with "$".
+	Prefix() string
+
+	// IsPreferred reports whether encoded already uses this Hasher's current
+	// (preferred) parameters, so a caller can tell a stale hash from a current one.
+	IsPreferred(encoded []byte) bool
+}
+
+// Passwd dispatches password hashing and verification across a set of Hashers,
+// choosing one to verify against by matching the prefix of a stored hash, and
+// always hashing new passwords with the first (preferred) Hasher in the list.
+// This lets a server migrate users transparently from a weaker algorithm, or an
+// older set of parameters, to a stronger one as they log in, mirroring the
+// ASP.NET-to-Go migration pwdatav3 itself was built for.
+type Passwd struct {
+	hashers []Hasher
+}
+
+// NewPasswd returns a Passwd that hashes new passwords with hashers[0] and verifies
+// stored hashes against whichever of hashers recognises their prefix. At least one
+// Hasher must be supplied, and the first is treated as preferred.
+func NewPasswd(hashers ...Hasher) *Passwd {
+	if len(hashers) == 0 {
+		panic("pwdatav3: NewPasswd requires at least one Hasher")
+	}
+	return &Passwd{hashers: hashers}
+}
+
+// Passwd verifies pw against stored. If stored is empty (no password set yet), it
+// instead returns a freshly hashed value from the preferred Hasher and ok is false.
+// Otherwise hash is stored unchanged and ok reports whether pw matched it, unless no
+// Hasher recognises stored, in which case hash is nil and err is ErrUnknownPrefix.
+func (p *Passwd) Passwd(pw, stored []byte) (hash []byte, ok bool, err error) {
+	if len(stored) == 0 {
+		hash, err = p.hashers[0].Hash(pw)
+		return hash, false, err
+	}
+	h := p.find(stored)
+	if h == nil {
+		return nil, false, ErrUnknownPrefix
+	}
+	ok, err = h.Verify(pw, stored)
+	return stored, ok, err
+}
+
+// IsPreferred reports whether stored was produced by the preferred (first) Hasher
+// using its current parameters. A server can call this after a successful Passwd
+// verify and, if it returns false, rehash and save the password without requiring
+// the user to reset it.
+func (p *Passwd) IsPreferred(stored []byte) bool {
+	pref := p.hashers[0]
+	return matchesPrefix(pref, stored) && pref.IsPreferred(stored)
+}
+
+// find returns the Hasher whose prefix matches stored, or nil if none does.
+func (p *Passwd) find(stored []byte) Hasher {
+	for _, h := range p.hashers {
+		if matchesPrefix(h, stored) {
+			return h
+		}
+	}
+	return nil
+}
+
+// matchesPrefix reports whether stored is (or could be) an encoded hash produced by h.
+// A Hasher with no textual prefix, such as the ASP.NET v3 binary form, claims anything
+// that isn't one of the "$name$..."-style encodings used by the others.
+func matchesPrefix(h Hasher, stored []byte) bool {
+	prefix := h.Prefix()
+	if prefix == "" {
+		return len(stored) > 0 && stored[0] != '$'
+	}
+	return bytes.HasPrefix(stored, []byte(prefix))
+}
@@ -0,0 +1,70 @@
+package pwdatav3
+
+import "testing"
+
+func TestPasswd(t *testing.T) {
+	aspnet := &ASPNetV3Hasher{Iter: DefaultIter}
+	bcryptH := &BcryptHasher{Cost: 4} // lowest allowed cost, to keep the test fast
+	p := NewPasswd(aspnet, bcryptH)
+
+	t.Run("new password", func(t *testing.T) {
+		hash, ok, err := p.Passwd([]byte("In2Egypt!"), nil)
+		if err != nil {
+			t.Fatalf("Passwd: got error %v", err)
+		}
+		if ok {
+			t.Errorf("Passwd with no stored hash: want ok=false; got true")
+		}
+		if !matchesPrefix(aspnet, hash) {
+			t.Errorf("Passwd with no stored hash: want a hash from the preferred (ASP.NET) hasher")
+		}
+	})
+
+	t.Run("verify preferred hasher", func(t *testing.T) {
+		stored, _, err := p.Passwd([]byte("In2Egypt!"), nil)
+		if err != nil {
+			t.Fatalf("Passwd: got error %v", err)
+		}
+		_, ok, err := p.Passwd([]byte("In2Egypt!"), stored)
+		if err != nil || !ok {
+			t.Errorf("Passwd verify correct password: got ok=%v err=%v", ok, err)
+		}
+		_, ok, err = p.Passwd([]byte("wrong"), stored)
+		if err != nil || ok {
+			t.Errorf("Passwd verify wrong password: got ok=%v err=%v", ok, err)
+		}
+		if !p.IsPreferred(stored) {
+			t.Errorf("IsPreferred: want true for a freshly hashed password")
+		}
+	})
+
+	t.Run("verify non-preferred hasher by prefix", func(t *testing.T) {
+		stored, err := bcryptH.Hash([]byte("In2Egypt!"))
+		if err != nil {
+			t.Fatalf("bcryptH.Hash: got error %v", err)
+		}
+		_, ok, err := p.Passwd([]byte("In2Egypt!"), stored)
+		if err != nil || !ok {
+			t.Errorf("Passwd verify bcrypt hash: got ok=%v err=%v", ok, err)
+		}
+		if p.IsPreferred(stored) {
+			t.Errorf("IsPreferred: want false for a hash from a non-preferred hasher")
+		}
+	})
+
+	t.Run("unknown prefix", func(t *testing.T) {
+		_, _, err := p.Passwd([]byte("In2Egypt!"), []byte("$unknownscheme$xyz"))
+		if err != ErrUnknownPrefix {
+			t.Errorf("Passwd with unrecognised prefix: want %v; got %v", ErrUnknownPrefix, err)
+		}
+	})
+
+	t.Run("NewPasswd requires a hasher", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("NewPasswd with no hashers: want a panic")
+			}
+		}()
+		NewPasswd()
+	})
+}
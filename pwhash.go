@@ -9,12 +9,19 @@ package pwdatav3
 import (
 	"bytes"
 	"crypto/rand"
+	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+
 	"golang.org/x/crypto/pbkdf2"
 )
 
@@ -24,21 +31,38 @@ var (
 	ErrVersion   = errors.New("unknown hashed format version")
 	ErrFunction  = errors.New("unknown hash function")
 	ErrParameter = errors.New("invalid hash function parameter")
+
+	// ErrMismatchedHashAndPassword is returned by CompareHashAndPassword when the given
+	// password does not match the stored hash.
+	ErrMismatchedHashAndPassword = errors.New("pwdatav3: hashedPassword is not the hash of the given password")
 )
 
 // PWHash represents a hashed value (version 3 for ASP.NET) using
 // PBKDF2 with HMAC-SHA256, and by default, 128-bit salt, 256-bit hash and 10000 iterations.
 type PWHash struct {
-	ver  uint8  // 0x01 => v3 (!)
-	prf  uint32 // 1 => sha256
+	ver  uint8 // 0x01 => v3 (!)
+	prf  PRF
 	iter uint32
 	salt []byte
 	hash []byte
 }
 
+// PRF identifies the HMAC hash function PBKDF2 is keyed with, using the same codes as
+// ASP.NET Identity's KeyDerivationPrf enum, so a stored value's prf field round-trips
+// unchanged between .NET and this package.
+type PRF uint32
+
+const (
+	// PRFSHA1 selects HMAC-SHA1 (20-byte digest).
+	PRFSHA1 PRF = 0
+	// PRFSHA256 selects HMAC-SHA256 (32-byte digest); this is ASP.NET's and this package's default.
+	PRFSHA256 PRF = 1
+	// PRFSHA512 selects HMAC-SHA512 (64-byte digest).
+	PRFSHA512 PRF = 2
+)
+
 const (
-	v3        = 1
-	prfSHA256 = 1
+	v3 = 1
 
 	// Default hash iterations used by ASP.NET.
 	DefaultIter = 10000
@@ -47,21 +71,65 @@ const (
 	DefaultSaltLen = 16
 )
 
+var (
+	// MaxIter is the largest iteration count UnmarshalBinary and UnmarshalPHC will accept
+	// for PRFSHA1 and PRFSHA256 hashes. Operators can raise it as hardware improves; ASP.NET's
+	// own ceiling is 100000.
+	MaxIter = 100000
+
+	// MaxIterSHA512 is the equivalent ceiling for PRFSHA512, set higher by default since
+	// SHA-512 costs little extra per iteration on modern 64-bit hardware.
+	MaxIterSHA512 = 600000
+)
+
+// maxIterFor returns the iteration ceiling UnmarshalBinary/UnmarshalPHC should enforce for prf.
+func maxIterFor(prf PRF) int {
+	if prf == PRFSHA512 {
+		return MaxIterSHA512
+	}
+	return MaxIter
+}
+
+// hashFuncFor returns the hash.Hash factory PBKDF2 should use for prf.
+func hashFuncFor(prf PRF) (func() hash.Hash, error) {
+	switch prf {
+	case PRFSHA1:
+		return sha1.New, nil
+	case PRFSHA256:
+		return sha256.New, nil
+	case PRFSHA512:
+		return sha512.New, nil
+	default:
+		return nil, ErrFunction
+	}
+}
+
 // New returns a hashed value for the given password and iterations (DefaultIter is an ASP.NET-compatible choice),
-// using a random salt that is DefaultSaltLen bytes long. It returns nil and an error only if it cannot make a random salt,
-// which suggests trouble with the underlying random number source.
+// using PRFSHA256 and a random salt that is DefaultSaltLen bytes long. It returns nil and an error only if it
+// cannot make a random salt, which suggests trouble with the underlying random number source.
 func New(pw string, iter int) (*PWHash, error) {
-	salt := make([]byte, DefaultSaltLen)
+	return NewWithPRF(pw, PRFSHA256, iter, DefaultSaltLen, sha256.Size)
+}
+
+// NewWithPRF is like New but lets the caller choose the PRF and the salt and hash lengths,
+// for compatibility with ASP.NET Identity configurations other than the default. hashLen is
+// the PBKDF2 output length in bytes; ASP.NET itself always uses prf's natural digest size
+// (20, 32 or 64), but PBKDF2 itself allows any length.
+func NewWithPRF(pw string, prf PRF, iter int, saltLen, hashLen int) (*PWHash, error) {
+	if _, err := hashFuncFor(prf); err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltLen)
 	_, err := rand.Read(salt)
 	if err != nil {
 		return nil, fmt.Errorf("cannot make salt value: %v", err)
 	}
 	pd := &PWHash{
 		ver:  v3,
-		prf:  prfSHA256,
+		prf:  prf,
 		iter: uint32(iter),
 		salt: salt,
-		hash: hashPW(pw, salt, iter),
+		hash: hashPW(pw, salt, iter, prf, hashLen),
 	}
 	return pd, nil
 }
@@ -69,15 +137,18 @@ func New(pw string, iter int) (*PWHash, error) {
 // Verify returns true iff the given plaintext password corresponds to the
 // value hashed in pd.
 func (pd *PWHash) Verify(pw string) bool {
-	dk := hashPW(pw, pd.salt, int(pd.iter))
+	dk := hashPW(pw, pd.salt, int(pd.iter), pd.prf, len(pd.hash))
 	return subtle.ConstantTimeCompare(pd.hash, dk) == 1
 }
 
 // hashPW applies the underlying key transformation to a plaintext password.
 // The other parameter values are typically extracted from an encoded PWHash in
-// an authentication database or supplied when that value was created.
-func hashPW(password string, salt []byte, iter int) []byte {
-	return pbkdf2.Key([]byte(password), salt, iter, sha256.Size, sha256.New)
+// an authentication database or supplied when that value was created. prf must
+// already be valid: callers (New, NewWithPRF, UnmarshalBinary, UnmarshalPHC) all
+// reject an unsupported prf before reaching here.
+func hashPW(password string, salt []byte, iter int, prf PRF, hashLen int) []byte {
+	h, _ := hashFuncFor(prf)
+	return pbkdf2.Key([]byte(password), salt, iter, hashLen, h)
 }
 
 // String returns the Base64 encoding.
@@ -87,7 +158,12 @@ func (pd *PWHash) String() string {
 }
 
 // UnmarshalText unmarshals a hashed value decoded from text, typically the value stored in a user table record.
+// It accepts either the default ASP.NET base64 binary form or the PHC string form produced by
+// [PWHash.MarshalPHC], sniffing a leading '$' to tell them apart.
 func (pd *PWHash) UnmarshalText(text []byte) error {
+	if len(text) > 0 && text[0] == '$' {
+		return pd.UnmarshalPHC(text)
+	}
 	out := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
 	n, err := base64.StdEncoding.Decode(out, text)
 	if err != nil {
@@ -116,7 +192,7 @@ const hdrLength = 1 + 3*4 // byte and 3 ints
 func (pd *PWHash) MarshalBinary() ([]byte, error) {
 	out := make([]byte, hdrLength+len(pd.salt)+len(pd.hash))
 	out[0] = pd.ver
-	binary.BigEndian.PutUint32(out[1:], pd.prf)
+	binary.BigEndian.PutUint32(out[1:], uint32(pd.prf))
 	binary.BigEndian.PutUint32(out[1+4:], pd.iter)
 	binary.BigEndian.PutUint32(out[1+4+4:], uint32(len(pd.salt)))
 	copy(out[hdrLength:], pd.salt)
@@ -136,19 +212,19 @@ func (pd *PWHash) UnmarshalBinary(a []byte) error {
 	if ver != v3 {
 		return ErrVersion
 	}
-	prf := binary.BigEndian.Uint32(a[1:])
-	if prf != prfSHA256 {
+	prf := PRF(binary.BigEndian.Uint32(a[1:]))
+	if _, err := hashFuncFor(prf); err != nil {
 		return ErrFunction
 	}
 	iter := binary.BigEndian.Uint32(a[1+4:])
-	if iter < 1 || iter > 100000 {
+	if iter < 1 || iter > uint32(maxIterFor(prf)) {
 		return ErrParameter
 	}
 	saltlen := binary.BigEndian.Uint32(a[1+4+4:])
 	if saltlen < 1 || saltlen > 64 {
 		return ErrParameter
 	}
-	if hdrLength+saltlen+sha256.Size != uint32(len(a)) {
+	if uint32(len(a)) <= hdrLength+saltlen {
 		return ErrCorrupt
 	}
 	pd.ver = ver
@@ -158,3 +234,190 @@ func (pd *PWHash) UnmarshalBinary(a []byte) error {
 	pd.hash = bytes.Clone(a[hdrLength+saltlen:])
 	return nil
 }
+
+// prfName returns the PHC algorithm identifier for a PRF code, so it can be preserved
+// across a MarshalPHC/UnmarshalPHC round trip.
+func prfName(prf PRF) (string, error) {
+	switch prf {
+	case PRFSHA1:
+		return "pbkdf2-sha1", nil
+	case PRFSHA256:
+		return "pbkdf2-sha256", nil
+	case PRFSHA512:
+		return "pbkdf2-sha512", nil
+	default:
+		return "", ErrFunction
+	}
+}
+
+// prfByName is the inverse of prfName.
+func prfByName(name string) (PRF, error) {
+	switch name {
+	case "pbkdf2-sha1":
+		return PRFSHA1, nil
+	case "pbkdf2-sha256":
+		return PRFSHA256, nil
+	case "pbkdf2-sha512":
+		return PRFSHA512, nil
+	default:
+		return 0, ErrFunction
+	}
+}
+
+// MarshalPHC returns pd encoded in the PHC string format used by argon2/scrypt-style
+// tooling such as htpasswd libraries and python's passlib, e.g.
+// "$pbkdf2-sha256$i=10000$<b64 salt>$<b64 hash>", with unpadded base64 fields. This
+// lets the same stored value be read by non-.NET tools; the ASP.NET base64 binary
+// form from MarshalText remains the default for new values.
+func (pd *PWHash) MarshalPHC() ([]byte, error) {
+	name, err := prfName(pd.prf)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("$%s$i=%d$%s$%s",
+		name, pd.iter,
+		base64.RawStdEncoding.EncodeToString(pd.salt),
+		base64.RawStdEncoding.EncodeToString(pd.hash))), nil
+}
+
+// UnmarshalPHC parses a PHC string previously produced by MarshalPHC. UnmarshalText
+// calls this automatically when text starts with '$', so callers can usually just
+// use UnmarshalText regardless of which form a stored value is in.
+func (pd *PWHash) UnmarshalPHC(text []byte) error {
+	fields := strings.Split(string(text), "$")
+	if len(fields) != 5 || fields[0] != "" {
+		return ErrCorrupt
+	}
+	prf, err := prfByName(fields[1])
+	if err != nil {
+		return err
+	}
+	iterField, ok := strings.CutPrefix(fields[2], "i=")
+	if !ok {
+		return ErrCorrupt
+	}
+	n, err := strconv.Atoi(iterField)
+	if err != nil {
+		return ErrCorrupt
+	}
+	if n < 1 || n > maxIterFor(prf) {
+		return ErrParameter
+	}
+	iter := uint32(n)
+	salt, err := base64.RawStdEncoding.DecodeString(fields[3])
+	if err != nil {
+		return fmt.Errorf("password encoding: %v", err)
+	}
+	if len(salt) < 1 || len(salt) > 64 {
+		return ErrParameter
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return fmt.Errorf("password encoding: %v", err)
+	}
+	if len(hash) == 0 {
+		return ErrCorrupt
+	}
+	pd.ver = v3
+	pd.prf = prf
+	pd.iter = iter
+	pd.salt = salt
+	pd.hash = hash
+	return nil
+}
+
+// MinCost is the smallest value GenerateFromPassword accepts for cost. Unlike bcrypt's
+// cost, which is an exponent (work doubling with each increment), cost here is the
+// literal PBKDF2 iteration count, so small bcrypt-style values such as bcrypt.DefaultCost
+// (10) would silently produce a near-worthless hash. MinCost rejects that mistake instead
+// of accepting it quietly.
+const MinCost = 1000
+
+// GenerateFromPassword returns the packed, encoded hash of password, hashed with cost
+// PBKDF2 iterations (not a bcrypt-style exponential work factor: see MinCost), in the
+// same form as [PWHash.MarshalText]. It is a near drop-in replacement for
+// golang.org/x/crypto/bcrypt's function of the same name, letting callers treat the
+// result as an opaque byte string rather than a *PWHash, but callers porting bcrypt
+// code must replace a bcrypt cost (4-31) with a real iteration count (DefaultIter is
+// a reasonable choice).
+func GenerateFromPassword(password []byte, cost int) ([]byte, error) {
+	if cost < MinCost {
+		return nil, fmt.Errorf("pwdatav3: cost %d is too low for a PBKDF2 iteration count (minimum %d): %w", cost, MinCost, ErrParameter)
+	}
+	pd, err := New(string(password), cost)
+	if err != nil {
+		return nil, err
+	}
+	return pd.MarshalText()
+}
+
+// CompareHashAndPassword compares a hash produced by GenerateFromPassword (or [PWHash.MarshalText])
+// with a plaintext password, returning nil if they match and ErrMismatchedHashAndPassword if they
+// don't. Any other error indicates a corrupt or unsupported hashed value.
+func CompareHashAndPassword(hashedPassword, password []byte) error {
+	var pd PWHash
+	if err := pd.UnmarshalText(hashedPassword); err != nil {
+		return err
+	}
+	if !pd.Verify(string(password)) {
+		return ErrMismatchedHashAndPassword
+	}
+	return nil
+}
+
+// Cost returns the iteration count used to produce hashedPassword, allowing a caller to
+// decide whether to rehash it with a stronger setting, analogous to bcrypt.Cost.
+func Cost(hashedPassword []byte) (int, error) {
+	var pd PWHash
+	if err := pd.UnmarshalText(hashedPassword); err != nil {
+		return 0, err
+	}
+	return int(pd.iter), nil
+}
+
+// Policy describes the minimum acceptable parameters for a stored PWHash. It is
+// consulted by NeedsRehash, typically after a successful Verify, to decide whether
+// the stored value should be replaced with one meeting current standards.
+//
+// PRF is a *PRF, not a PRF, because PRFSHA1 is itself the zero value of PRF: a plain
+// PRF field would make a Policy built without explicitly setting it silently require
+// SHA1, rehashing every healthy PRFSHA256 or PRFSHA512 hash. A nil PRF means "don't
+// enforce a particular PRF".
+type Policy struct {
+	MinIter int  // minimum acceptable iteration count
+	PRF     *PRF // required PRF, or nil to accept any
+	SaltLen int  // minimum acceptable salt length in bytes
+	HashLen int  // minimum acceptable hash length in bytes
+}
+
+// NeedsRehash reports whether pd falls short of policy in iteration count, PRF, or
+// salt/hash length, and so should be replaced by a fresh hash of the same password
+// on its next successful Verify.
+func (pd *PWHash) NeedsRehash(policy Policy) bool {
+	return int(pd.iter) < policy.MinIter ||
+		(policy.PRF != nil && pd.prf != *policy.PRF) ||
+		len(pd.salt) < policy.SaltLen ||
+		len(pd.hash) < policy.HashLen
+}
+
+// CalibrateIterations benchmarks hashPW on the current machine with PRFSHA256 and
+// returns an iteration count whose running time is close to target, the same way
+// applications tune a bcrypt cost to a target latency (eg. 250ms). Because PBKDF2's
+// cost scales linearly in the iteration count, one short probe run is enough to
+// extrapolate; CalibrateIterations itself therefore takes about as long as probeIter
+// iterations take to run, not as long as target.
+func CalibrateIterations(target time.Duration) int {
+	const probeIter = 10000
+	salt := make([]byte, DefaultSaltLen)
+	start := time.Now()
+	hashPW("calibrate", salt, probeIter, PRFSHA256, sha256.Size)
+	elapsed := time.Since(start)
+	if elapsed <= 0 {
+		return probeIter
+	}
+	iter := int(float64(probeIter) * float64(target) / float64(elapsed))
+	if iter < 1 {
+		iter = 1
+	}
+	return iter
+}
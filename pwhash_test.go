@@ -2,8 +2,10 @@ package pwdatav3
 
 import (
 	"bytes"
+	"crypto/sha256"
 	_ "fmt"
 	"testing"
+	"time"
 )
 
 type testuser struct {
@@ -36,13 +38,13 @@ type testhash struct {
 var hashes = []testhash{
 	{[]byte{0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, ErrCorrupt},
 	{[]byte{0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, ErrVersion},
-	{[]byte{v3, 0, 0, 0, 0, 4, 5, 6, 7, 8, 9, 10, 11}, ErrFunction},
-	{[]byte{v3, 0, 0, 0, byte(prfSHA256), 4, 5, 6, 7, 8, 9, 10, 11}, ErrParameter},
-	{[]byte{v3, 0, 0, 0, byte(prfSHA256), 0, 0, 0, 0, 8, 9, 10, 11}, ErrParameter},
-	{[]byte{v3, 0, 0, 0, byte(prfSHA256), 0, 0, 0, 1, 8, 9, 10, 11}, ErrParameter},
-	{[]byte{v3, 0, 0, 0, byte(prfSHA256), 0, 0, 0, 1, 0, 0, 0, 1}, ErrCorrupt},
-	{[]byte{v3, 0, 0, 0, byte(prfSHA256), 0, 0, 0, 1, 0, 0, 0, 1, 0xEE}, ErrCorrupt},
-	{append([]byte{v3, 0, 0, 0, byte(prfSHA256), 0, 0, 0, 1, 0, 0, 0, 1, 0xEE}, hashPW("hello", []byte{0xEE}, 1)...), nil},
+	{[]byte{v3, 0, 0, 0, 3, 4, 5, 6, 7, 8, 9, 10, 11}, ErrFunction},
+	{[]byte{v3, 0, 0, 0, byte(PRFSHA256), 4, 5, 6, 7, 8, 9, 10, 11}, ErrParameter},
+	{[]byte{v3, 0, 0, 0, byte(PRFSHA256), 0, 0, 0, 0, 8, 9, 10, 11}, ErrParameter},
+	{[]byte{v3, 0, 0, 0, byte(PRFSHA256), 0, 0, 0, 1, 8, 9, 10, 11}, ErrParameter},
+	{[]byte{v3, 0, 0, 0, byte(PRFSHA256), 0, 0, 0, 1, 0, 0, 0, 1}, ErrCorrupt},
+	{[]byte{v3, 0, 0, 0, byte(PRFSHA256), 0, 0, 0, 1, 0, 0, 0, 1, 0xEE}, ErrCorrupt},
+	{append([]byte{v3, 0, 0, 0, byte(PRFSHA256), 0, 0, 0, 1, 0, 0, 0, 1, 0xEE}, hashPW("hello", []byte{0xEE}, 1, PRFSHA256, sha256.Size)...), nil},
 }
 
 func toBase64(pwd *PWHash) (string, error) {
@@ -109,12 +111,12 @@ func TestPWHash(t *testing.T) {
 			if err != nil {
 				t.Errorf("%s: error decoding; got %v", user.name, err)
 			}
-			dk := hashPW(user.pw, pwd.salt, int(pwd.iter))
+			dk := hashPW(user.pw, pwd.salt, int(pwd.iter), pwd.prf, len(pwd.hash))
 			if !bytes.Equal(dk, pwd.hash) {
 				t.Errorf("%s: hashed value not equal; want %#v got %#v", user.name, pwd.hash, dk)
 			}
 			//fmt.Printf("%s: %#v\n", user.name, dk)
-			dk = hashPW(user.pw+"X", pwd.salt, int(pwd.iter))
+			dk = hashPW(user.pw+"X", pwd.salt, int(pwd.iter), pwd.prf, len(pwd.hash))
 			if bytes.Equal(dk, pwd.hash) {
 				t.Errorf("%s: hashed values unexpectedly equal", user.name)
 			}
@@ -129,6 +131,93 @@ func TestPWHash(t *testing.T) {
 			}
 		}
 	})
+	t.Run("NewWithPRF", func(t *testing.T) {
+		for _, prf := range []PRF{PRFSHA1, PRFSHA256, PRFSHA512} {
+			pd, err := NewWithPRF("In2Egypt!", prf, DefaultIter, DefaultSaltLen, DefaultSaltLen)
+			if err != nil {
+				t.Errorf("prf %d: NewWithPRF: got error %v", prf, err)
+				continue
+			}
+			if !pd.Verify("In2Egypt!") {
+				t.Errorf("prf %d: failed to verify correct password", prf)
+			}
+			if pd.Verify("In2Egypt!?") {
+				t.Errorf("prf %d: wrong password still verified", prf)
+			}
+			bin, err := pd.MarshalBinary()
+			if err != nil {
+				t.Errorf("prf %d: MarshalBinary: got error %v", prf, err)
+				continue
+			}
+			var round PWHash
+			if err := round.UnmarshalBinary(bin); err != nil {
+				t.Errorf("prf %d: UnmarshalBinary: got error %v", prf, err)
+				continue
+			}
+			if round.prf != prf {
+				t.Errorf("prf %d: not preserved across binary round trip: got %d", prf, round.prf)
+			}
+			if !round.Verify("In2Egypt!") {
+				t.Errorf("prf %d: binary round trip failed to verify correct password", prf)
+			}
+			phc, err := pd.MarshalPHC()
+			if err != nil {
+				t.Errorf("prf %d: MarshalPHC: got error %v", prf, err)
+				continue
+			}
+			var roundPHC PWHash
+			if err := roundPHC.UnmarshalText(phc); err != nil {
+				t.Errorf("prf %d: UnmarshalText(PHC): got error %v", prf, err)
+				continue
+			}
+			if roundPHC.prf != prf {
+				t.Errorf("prf %d: not preserved across PHC round trip: got %d", prf, roundPHC.prf)
+			}
+		}
+		if _, err := NewWithPRF("pw", PRF(99), DefaultIter, DefaultSaltLen, DefaultSaltLen); err != ErrFunction {
+			t.Errorf("NewWithPRF with unknown prf: want %v; got %v", ErrFunction, err)
+		}
+	})
+	t.Run("PHC", func(t *testing.T) {
+		for _, user := range testusers {
+			pwd, err := fromBase64(user.b64)
+			if err != nil {
+				t.Errorf("%s: error decoding [%s]; got %v", user.name, user.b64, err)
+				continue
+			}
+			phc, err := pwd.MarshalPHC()
+			if err != nil {
+				t.Errorf("%s: error marshalling PHC; got %v", user.name, err)
+				continue
+			}
+			var round PWHash
+			if err := round.UnmarshalText(phc); err != nil {
+				t.Errorf("%s: error unmarshalling PHC %q; got %v", user.name, phc, err)
+				continue
+			}
+			if !round.Verify(user.pw) {
+				t.Errorf("%s: PHC round trip failed to verify correct password %q", user.name, user.pw)
+			}
+			if round.Verify(user.pw + "?") {
+				t.Errorf("%s: PHC round trip verified wrong password", user.name)
+			}
+			if round.prf != pwd.prf {
+				t.Errorf("%s: PRF identifier not preserved across PHC round trip: want %d got %d", user.name, pwd.prf, round.prf)
+			}
+			phc2, err := round.MarshalPHC()
+			if err != nil {
+				t.Errorf("%s: error re-marshalling PHC; got %v", user.name, err)
+				continue
+			}
+			if string(phc) != string(phc2) {
+				t.Errorf("%s: PHC encoding not stable: want %s got %s", user.name, phc, phc2)
+			}
+		}
+		var junk PWHash
+		if err := junk.UnmarshalPHC([]byte("$pbkdf2-sha256$i=10000x$YWJjZA$YWJjZA")); err != ErrCorrupt {
+			t.Errorf("UnmarshalPHC with trailing garbage in iteration field: want %v; got %v", ErrCorrupt, err)
+		}
+	})
 	t.Run("GenerateFromPassword", func(t *testing.T) {
 		for _, user := range testusers {
 			pw := []byte(user.pw) // bad planning
@@ -147,5 +236,37 @@ func TestPWHash(t *testing.T) {
 				}
 			}
 		}
+		if _, err := GenerateFromPassword([]byte("In2Egypt!"), 10); err == nil {
+			t.Errorf("GenerateFromPassword with a bcrypt-style cost: want an error rejecting it below MinCost")
+		}
+	})
+	t.Run("NeedsRehash", func(t *testing.T) {
+		pd, err := NewWithPRF("In2Egypt!", PRFSHA1, 1000, 8, 8)
+		if err != nil {
+			t.Fatalf("NewWithPRF: got error %v", err)
+		}
+		wantPRF := PRFSHA256
+		policy := Policy{MinIter: DefaultIter, PRF: &wantPRF, SaltLen: DefaultSaltLen, HashLen: sha256.Size}
+		if !pd.NeedsRehash(policy) {
+			t.Errorf("hash well below policy: want NeedsRehash true")
+		}
+		strong, err := NewWithPRF("In2Egypt!", PRFSHA256, DefaultIter, DefaultSaltLen, sha256.Size)
+		if err != nil {
+			t.Fatalf("NewWithPRF: got error %v", err)
+		}
+		if strong.NeedsRehash(policy) {
+			t.Errorf("hash meeting policy: want NeedsRehash false")
+		}
+		// A Policy built without setting PRF must not silently demand PRFSHA1 (its zero value).
+		noPRFPolicy := Policy{MinIter: DefaultIter, SaltLen: DefaultSaltLen, HashLen: sha256.Size}
+		if strong.NeedsRehash(noPRFPolicy) {
+			t.Errorf("hash meeting policy with PRF left unset: want NeedsRehash false")
+		}
+	})
+	t.Run("CalibrateIterations", func(t *testing.T) {
+		iter := CalibrateIterations(10 * time.Millisecond)
+		if iter < 1 {
+			t.Errorf("CalibrateIterations: want at least 1 iteration; got %d", iter)
+		}
 	})
 }